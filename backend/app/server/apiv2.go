@@ -0,0 +1,298 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/pkg/errors"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/umputun/secrets/backend/app/messager"
+)
+
+// problem is an RFC 7807 application/problem+json (or +xml) error document.
+// Code is a stable, machine-branchable identifier on top of the free-text
+// Detail, e.g. "pin_size_invalid" or "pin_attempt_failed".
+type problem struct {
+	XMLName xml.Name `json:"-" xml:"problem"`
+	Type    string   `json:"type" xml:"type"`
+	Title   string   `json:"title" xml:"title"`
+	Status  int      `json:"status" xml:"status"`
+	Detail  string   `json:"detail" xml:"detail"`
+	Code    string   `json:"code" xml:"code"`
+}
+
+// writeProblem emits a problem document at the given status, content-negotiated
+// the same way writeResponse negotiates a success payload.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	p := problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+	writeResponse(w, r, status, p, true)
+}
+
+// writeResponse inspects the Accept header and encodes payload as JSON, XML
+// or form-urlencoded accordingly, defaulting to JSON. problemDoc controls
+// the content-type used for JSON/XML: application/problem+{json,xml} for
+// errors, application/{json,xml} otherwise.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, payload interface{}, problemDoc bool) {
+	render.Status(r, status)
+
+	switch negotiateAccept(r) {
+	case "application/xml", "text/xml":
+		ct := "application/xml"
+		if problemDoc {
+			ct = "application/problem+xml"
+		}
+		w.Header().Set("Content-Type", ct)
+		w.WriteHeader(status)
+		if err := xml.NewEncoder(w).Encode(payload); err != nil {
+			log.Printf("[WARN] failed to encode xml response, %v", err)
+		}
+	case "application/x-www-form-urlencoded":
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(toURLValues(payload).Encode()))
+	default:
+		ct := "application/json; charset=utf-8"
+		if problemDoc {
+			ct = "application/problem+json; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", ct)
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(payload); err != nil {
+			log.Printf("[WARN] failed to encode json response, %v", err)
+		}
+	}
+}
+
+// decodeRequest binds the request body into dst based on Content-Type,
+// supporting application/json, application/xml and
+// application/x-www-form-urlencoded, mirroring writeResponse's negotiation
+// on the way in.
+func decodeRequest(r *http.Request, dst interface{}) error {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch ct {
+	case "application/xml", "text/xml":
+		return xml.NewDecoder(r.Body).Decode(dst)
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+		return fromURLValues(r.PostForm, dst)
+	default:
+		return json.NewDecoder(r.Body).Decode(dst)
+	}
+}
+
+// negotiateAccept returns the first content-type in the Accept header this
+// server knows how to produce, defaulting to JSON.
+func negotiateAccept(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	for _, part := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case "application/xml", "text/xml", "application/x-www-form-urlencoded", "application/json":
+			return mt
+		}
+	}
+	return "application/json"
+}
+
+// POST /api/v2/message
+func (s Server) saveMessageCtrlV2(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		Message string `json:"message" xml:"message"`
+		Exp     int    `json:"exp" xml:"exp"`
+		Pin     string `json:"pin" xml:"pin"`
+	}{}
+
+	if err := decodeRequest(r, &request); err != nil {
+		log.Printf("[WARN] can't bind v2 request %v", request)
+		writeProblem(w, r, http.StatusBadRequest, "request_invalid", err.Error())
+		return
+	}
+
+	if len(request.Pin) != s.PinSize {
+		log.Printf("[WARN] incorrect pin size %d", len(request.Pin))
+		writeProblem(w, r, http.StatusBadRequest, "pin_size_invalid", "incorrect pin size")
+		return
+	}
+
+	msg, err := s.Messager.MakeMessage(time.Second*time.Duration(request.Exp), request.Message, request.Pin)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "message_save_failed", err.Error())
+		return
+	}
+	messagesCreatedTotal.Inc()
+	messageSizeBytes.Observe(float64(len(request.Message)))
+
+	writeResponse(w, r, http.StatusCreated, JSON{"key": msg.Key, "exp": msg.Exp}, false)
+}
+
+// GET /api/v2/message/{key}?pin=...
+func (s Server) getMessageCtrlV2(w http.ResponseWriter, r *http.Request) {
+	key, pin := chi.URLParam(r, "key"), r.URL.Query().Get("pin")
+	if key == "" || len(pin) != s.PinSize {
+		log.Print("[WARN] no valid key or pin in v2 get request")
+		writeProblem(w, r, http.StatusBadRequest, "pin_size_invalid", "no key or pin passed")
+		return
+	}
+
+	serveRequest := func() (status int, code string, res JSON) {
+		pinAttemptsTotal.Inc()
+		msg, err := s.Messager.LoadMessage(key, pin)
+		messagesReadTotal.WithLabelValues(readResult(err)).Inc()
+		if err != nil {
+			log.Printf("[WARN] failed to load key %v", key)
+			if err == messager.ErrBadPinAttempt {
+				return http.StatusExpectationFailed, "pin_attempt_failed", JSON{"detail": err.Error()}
+			}
+			return http.StatusBadRequest, "message_load_failed", JSON{"detail": err.Error()}
+		}
+		return http.StatusOK, "", JSON{"key": msg.Key, "message": string(msg.Data)}
+	}
+
+	// make sure serveRequest works constant time on any branch, same as v1
+	st := time.Now()
+	status, code, res := serveRequest()
+	time.Sleep(time.Millisecond*100 - time.Since(st))
+
+	if code != "" {
+		writeProblem(w, r, status, code, res["detail"].(string))
+		return
+	}
+	writeResponse(w, r, status, res, false)
+}
+
+// GET /api/v2/params
+func (s Server) getParamsCtrlV2(w http.ResponseWriter, r *http.Request) {
+	params := struct {
+		PinSize        int `json:"pin_size" xml:"pin_size"`
+		MaxPinAttempts int `json:"max_pin_attempts" xml:"max_pin_attempts"`
+		MaxExpSecs     int `json:"max_exp_sec" xml:"max_exp_sec"`
+	}{
+		PinSize:        s.PinSize,
+		MaxPinAttempts: s.MaxPinAttempts,
+		MaxExpSecs:     int(s.MaxExpire.Seconds()),
+	}
+	writeResponse(w, r, http.StatusOK, params, false)
+}
+
+// toURLValues flattens a JSON-taggable struct or map into url.Values for the
+// application/x-www-form-urlencoded response encoding.
+func toURLValues(payload interface{}) url.Values {
+	out := url.Values{}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return out
+	}
+
+	flat := map[string]interface{}{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return out
+	}
+
+	for k, v := range flat {
+		out.Set(k, toString(v))
+	}
+	return out
+}
+
+// fromURLValues decodes form values into dst, a pointer to a struct, using
+// the same json tags writeResponse/toURLValues use for the response side.
+// Unlike a stringify-then-json.Unmarshal round trip, it converts each value
+// to the destination field's actual kind, so numeric and boolean fields
+// (e.g. saveMessageCtrlV2's Exp int) decode correctly instead of failing
+// with "cannot unmarshal string into Go struct field".
+func fromURLValues(values url.Values, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("fromURLValues: dst must be a pointer to a struct")
+	}
+	elem := v.Elem()
+	t := elem.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			name = strings.Split(tag, ",")[0]
+		}
+
+		if !values.Has(name) {
+			continue
+		}
+
+		fv := elem.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+
+		if err := setFromString(fv, values.Get(name)); err != nil {
+			return errors.Wrapf(err, "failed to decode form field %q", name)
+		}
+	}
+
+	return nil
+}
+
+// setFromString converts raw into fv's kind and assigns it, covering the
+// field kinds this server's request structs actually use.
+func setFromString(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return errors.Errorf("unsupported field kind %s", fv.Kind())
+	}
+	return nil
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	default:
+		data, _ := json.Marshal(t)
+		return string(data)
+	}
+}
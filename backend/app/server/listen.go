@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// listenFDsStart is the first inherited file descriptor under the systemd
+// socket activation protocol, mirroring coreos/go-systemd's listenfd.
+const listenFDsStart = 3
+
+// listen returns a net.Listener for addr, adopting a systemd-activated
+// socket when LISTEN_FDS is set so operators can restart with zero
+// downtime, or dialing a fresh listener otherwise.
+func (s Server) listen(addr string) (net.Listener, error) {
+	if l, err := listenersFromSystemd(); err == nil && len(l) > 0 {
+		log.Printf("[INFO] adopting socket-activated listener, ignoring listen addr %s", addr)
+		return l[0], nil
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// listenersFromSystemd adopts the file descriptors passed by systemd socket
+// activation (LISTEN_FDS/LISTEN_PID), if any. It returns an empty slice,
+// not an error, when activation env vars aren't set.
+func listenersFromSystemd() ([]net.Listener, error) {
+	pid, nfds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if pid == "" || nfds == "" {
+		return nil, nil
+	}
+
+	if p, err := strconv.Atoi(pid); err != nil || p != os.Getpid() {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(nfds)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid LISTEN_FDS")
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		f := os.NewFile(uintptr(fd), "listen-fd-"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to adopt fd %d", fd)
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
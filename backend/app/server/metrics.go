@@ -0,0 +1,169 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/umputun/secrets/backend/app/messager"
+)
+
+// Prometheus collectors instrumenting saveMessageCtrl, getMessageCtrl and
+// their v2/upload counterparts at the exact decision points already
+// present in each handler.
+var (
+	messagesCreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "secrets_messages_created_total",
+		Help: "Total number of secrets stored.",
+	})
+
+	messagesReadTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "secrets_messages_read_total",
+		Help: "Total number of secret read attempts, by outcome.",
+	}, []string{"result"})
+
+	pinAttemptsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "secrets_pin_attempts_total",
+		Help: "Total number of pin verification attempts.",
+	})
+
+	messageSizeBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "secrets_message_size_bytes",
+		Help:    "Size distribution of stored secrets, in bytes.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	})
+
+	activeUploadSessions = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "secrets_active_upload_sessions",
+		Help: "Number of currently open resumable upload sessions.",
+	}, func() float64 { return float64(uploads.count()) })
+
+	storeSizeBytes = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "secrets_store_size_bytes",
+		Help: "Size of the configured secret store, in bytes, when the backend reports one.",
+	}, func() float64 { return storeSizeFn() })
+)
+
+// storeSizeFn backs the store_size_bytes gauge; configureMetrics replaces
+// it with one bound to Server.Storage once Run knows the live config, since
+// the gauge itself is registered at package init before any Server exists.
+var storeSizeFn = func() float64 { return 0 }
+
+// configureMetrics points storeSizeFn at Server.Storage's primary store,
+// when it reports a size, so the store-size gauge isn't always zero once
+// a storage.Manager is actually configured.
+func configureMetrics(s Server) {
+	storeSizeFn = func() float64 {
+		if s.Storage == nil || s.Storage.Primary == nil {
+			return 0
+		}
+		sz, ok := s.Storage.Primary.(interface{ Size() (int64, error) })
+		if !ok {
+			return 0
+		}
+		n, err := sz.Size()
+		if err != nil {
+			return 0
+		}
+		return float64(n)
+	}
+}
+
+// readResult classifies a Messager.LoadMessage error into the "result"
+// label used by messagesReadTotal. The bad-pin sentinel maps to bad_pin, an
+// error whose text mentions "expired" maps to expired, and any other error
+// is reported as not_found.
+func readResult(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if err == messager.ErrBadPinAttempt {
+		return "bad_pin"
+	}
+	if strings.Contains(err.Error(), "expired") {
+		return "expired"
+	}
+	return "not_found"
+}
+
+// adminAuth guards /admin with a bearer token loaded from Server.AdminToken.
+// An empty AdminToken disables the subsystem entirely.
+func (s Server) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.AdminToken == "" {
+			writeProblem(w, r, http.StatusNotFound, "not_found", "not found")
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+			writeProblem(w, r, http.StatusUnauthorized, "admin_token_invalid", "invalid or missing admin token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GET /admin/config, the effective non-secret configuration
+func (s Server) adminConfigCtrl(w http.ResponseWriter, r *http.Request) {
+	listenAddr := s.ListenAddr
+	if listenAddr == "" {
+		listenAddr = ":8080"
+	}
+	tlsMode := s.TLSMode
+	if tlsMode == "" {
+		tlsMode = tlsModeNone
+	}
+
+	cfg := struct {
+		Version        string `json:"version"`
+		Domain         string `json:"domain"`
+		PinSize        int    `json:"pin_size"`
+		MaxPinAttempts int    `json:"max_pin_attempts"`
+		MaxExpSecs     int    `json:"max_exp_sec"`
+		TLSMode        string `json:"tls_mode"`
+		ListenAddr     string `json:"listen_addr"`
+		UploadMaxSize  int64  `json:"upload_max_size"`
+	}{
+		Version:        s.Version,
+		Domain:         s.Domain,
+		PinSize:        s.PinSize,
+		MaxPinAttempts: s.MaxPinAttempts,
+		MaxExpSecs:     int(s.MaxExpire.Seconds()),
+		TLSMode:        tlsMode,
+		ListenAddr:     listenAddr,
+		UploadMaxSize:  uploads.maxTotalSize,
+	}
+	writeResponse(w, r, http.StatusOK, cfg, false)
+}
+
+// GET /admin/rules, currently active limiter/throttle settings
+func (s Server) adminRulesCtrl(w http.ResponseWriter, r *http.Request) {
+	rules := struct {
+		RequestsPerSecond float64 `json:"requests_per_second"`
+		ThrottleLimit     int     `json:"throttle_limit"`
+		SizeLimitBytes    int     `json:"size_limit_bytes"`
+		UploadMaxSessions int     `json:"upload_max_sessions"`
+	}{
+		RequestsPerSecond: 10,
+		ThrottleLimit:     1000,
+		SizeLimitBytes:    64 * 1024,
+		UploadMaxSessions: uploads.maxSessions,
+	}
+	writeResponse(w, r, http.StatusOK, rules, false)
+}
+
+func mountAdmin(router chi.Router, s Server) {
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(s.adminAuth)
+		r.Handle("/metrics", promhttp.Handler())
+		r.Get("/config", s.adminConfigCtrl)
+		r.Get("/rules", s.adminRulesCtrl)
+	})
+}
@@ -20,12 +20,14 @@ import (
 	um "github.com/go-pkgz/rest"
 
 	"github.com/umputun/secrets/backend/app/messager"
+	"github.com/umputun/secrets/backend/app/storage"
 	"github.com/umputun/secrets/backend/app/store"
 )
 
 // Server is a rest with store
 type Server struct {
 	Messager       Messager
+	Storage        *storage.Manager // optional, backs Messager when set instead of a hardwired single store
 	PinSize        int
 	MaxPinAttempts int
 	MaxExpire      time.Duration
@@ -33,6 +35,22 @@ type Server struct {
 	Version        string
 	Domain         string
 	TemplateCache  map[string]*template.Template
+	SigningKey     []byte        // HS256 secret for signed recipient links, see issueRecipientToken
+	TokenTTL       time.Duration // how long a signed recipient link stays redeemable, defaults to 15m
+
+	ListenAddr       string        // address to listen on, defaults to ":8080"
+	TLSMode          string        // "none" (default), "file" or "autocert"
+	TLSCertFile      string        // used when TLSMode is "file"
+	TLSKeyFile       string        // used when TLSMode is "file"
+	AutocertDomains  []string      // extra domains to include alongside Domain, used when TLSMode is "autocert"
+	AutocertCacheDir string        // used when TLSMode is "autocert"
+	ShutdownTimeout  time.Duration // grace period for in-flight requests on shutdown, defaults to 30s
+
+	UploadMaxLifetime time.Duration // how long a resumable upload session may stay open, defaults to 1h
+	UploadMaxSize     int64         // max total bytes an upload session may accumulate, defaults to 64MB
+	UploadMaxSessions int           // max concurrent upload sessions before the oldest is evicted, defaults to 1000
+
+	AdminToken string // bearer token guarding /admin; the subsystem is disabled when empty
 }
 
 // Messager interface making and loading messages
@@ -41,28 +59,76 @@ type Messager interface {
 	LoadMessage(key, pin string) (msg *store.Message, err error)
 }
 
-// Run the lister and request's router, activate rest server
+// Run the listener and request's router, activate rest server. The listen
+// address, TLS mode and socket source are all driven by the matching
+// Server fields; see listen.go and tls.go for the supporting pieces.
 func (s Server) Run(ctx context.Context) error {
 	log.Printf("[INFO] activate rest server")
 
+	if s.Storage != nil {
+		if err := s.Storage.Health(ctx); err != nil {
+			log.Printf("[WARN] storage manager unhealthy at startup, %v", err)
+		}
+		s.Messager = messager.New(s.Storage)
+	}
+	configureUploads(s)
+	configureMetrics(s)
+
+	addr := s.ListenAddr
+	if addr == "" {
+		addr = ":8080"
+	}
+
 	httpServer := &http.Server{
-		Addr:              ":8080",
+		Addr:              addr,
 		Handler:           s.routes(),
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      30 * time.Second,
 		IdleTimeout:       30 * time.Second,
 	}
 
+	listener, err := s.listen(addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire listener")
+	}
+
+	var autocertMgr *autocertManager
+	if s.TLSMode == tlsModeAutocert {
+		autocertMgr, err = s.newAutocertManager()
+		if err != nil {
+			return errors.Wrap(err, "failed to set up autocert")
+		}
+		go autocertMgr.serveHTTPChallengeAndRedirect()
+		httpServer.TLSConfig = autocertMgr.TLSConfig()
+	}
+
+	shutdownTimeout := s.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+
 	go func() {
 		<-ctx.Done()
-		if httpServer != nil {
-			if clsErr := httpServer.Close(); clsErr != nil {
-				log.Printf("[ERROR] failed to close proxy http server, %v", clsErr)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if shErr := httpServer.Shutdown(shutdownCtx); shErr != nil {
+			log.Printf("[ERROR] failed to shut down rest server gracefully, %v", shErr)
+		}
+		if autocertMgr != nil {
+			if shErr := autocertMgr.Shutdown(shutdownCtx); shErr != nil {
+				log.Printf("[ERROR] failed to shut down autocert challenge server, %v", shErr)
 			}
 		}
 	}()
 
-	err := httpServer.ListenAndServe()
+	switch s.TLSMode {
+	case tlsModeFile:
+		err = httpServer.ServeTLS(listener, s.TLSCertFile, s.TLSKeyFile)
+	case tlsModeAutocert:
+		err = httpServer.ServeTLS(listener, "", "")
+	default:
+		err = httpServer.Serve(listener)
+	}
 	log.Printf("[WARN] http server terminated, %s", err)
 
 	if !errors.Is(err, http.ErrServerClosed) {
@@ -86,11 +152,34 @@ func (s Server) routes() chi.Router {
 		r.Get("/params", s.getParamsCtrl)
 	})
 
+	// /api/v2 keeps the v1 semantics but adds content negotiation and
+	// RFC 7807 problem+json errors so clients can branch on a stable code
+	// instead of parsing free-text error strings.
+	router.Route("/api/v2", func(r chi.Router) {
+		r.Use(Logger(log.Default()))
+		r.Post("/message", s.saveMessageCtrlV2)
+		r.Get("/message/{key}", s.getMessageCtrlV2)
+		r.Get("/params", s.getParamsCtrlV2)
+
+		r.Route("/uploads", func(r chi.Router) {
+			r.Post("/", s.createUploadCtrl)
+			r.Patch("/{uuid}", s.patchUploadCtrl)
+			r.Put("/{uuid}", s.finalizeUploadCtrl)
+		})
+	})
+
+	mountAdmin(router, s)
+
 	router.Get("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
 		render.PlainText(w, r, "User-agent: *\nDisallow: /api/\nDisallow: /show/\n")
 	})
 
 	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/v2") {
+			writeProblem(w, r, http.StatusNotFound, "not_found", "not found")
+			return
+		}
+
 		if strings.HasPrefix(r.URL.Path, "/api/v1") {
 			render.Status(r, http.StatusNotFound)
 			render.JSON(w, r, JSON{"error": "not found"})
@@ -105,6 +194,7 @@ func (s Server) routes() chi.Router {
 	router.Get("/message/{key}", s.showMessageView)
 	router.Post("/load-message", s.loadMessage)
 	router.Get("/about", s.aboutView)
+	router.Get("/r/{token}", s.loadRecipientLinkCtrl)
 
 	s.fileServer(router, "/", truncatedFileSystem{http.Dir(s.WebRoot)})
 
@@ -114,9 +204,10 @@ func (s Server) routes() chi.Router {
 // POST /v1/message
 func (s Server) saveMessageCtrl(w http.ResponseWriter, r *http.Request) {
 	request := struct {
-		Message string
-		Exp     int
-		Pin     string
+		Message  string
+		Exp      int
+		Pin      string
+		LinkMode string `json:"link_mode"`
 	}{}
 
 	if err := render.DecodeJSON(r.Body, &request); err != nil {
@@ -139,6 +230,22 @@ func (s Server) saveMessageCtrl(w http.ResponseWriter, r *http.Request) {
 		render.JSON(w, r, JSON{"error": err.Error()})
 		return
 	}
+	messagesCreatedTotal.Inc()
+	messageSizeBytes.Observe(float64(len(request.Message)))
+
+	if request.LinkMode == "signed" {
+		token, tokErr := s.issueRecipientToken(msg.Key, request.Pin)
+		if tokErr != nil {
+			log.Printf("[WARN] can't issue recipient token, %v", tokErr)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, JSON{"error": tokErr.Error()})
+			return
+		}
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, JSON{"url": "/r/" + token, "exp": msg.Exp})
+		return
+	}
+
 	render.Status(r, http.StatusCreated)
 	render.JSON(w, r, JSON{"key": msg.Key, "exp": msg.Exp})
 }
@@ -155,7 +262,9 @@ func (s Server) getMessageCtrl(w http.ResponseWriter, r *http.Request) {
 	}
 
 	serveRequest := func() (status int, res JSON) {
+		pinAttemptsTotal.Inc()
 		msg, err := s.Messager.LoadMessage(key, pin)
+		messagesReadTotal.WithLabelValues(readResult(err)).Inc()
 		if err != nil {
 			log.Printf("[WARN] failed to load key %v", key)
 			if err == messager.ErrBadPinAttempt {
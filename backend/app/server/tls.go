@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme/autocert"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// TLS modes accepted by Server.TLSMode.
+const (
+	tlsModeNone     = "none"
+	tlsModeFile     = "file"
+	tlsModeAutocert = "autocert"
+)
+
+// autocertManager wraps autocert.Manager with the HTTP-01 challenge
+// handler and the :80 -> :443 redirect Run wires up in autocert mode. The
+// challenge server is built eagerly so Shutdown is always safe to call,
+// even if the caller never started serveHTTPChallengeAndRedirect.
+type autocertManager struct {
+	mgr          *autocert.Manager
+	challengeSrv *http.Server
+}
+
+// newAutocertManager builds an autocert.Manager for Domain plus any
+// AutocertDomains, caching certificates under AutocertCacheDir.
+func (s Server) newAutocertManager() (*autocertManager, error) {
+	domains := append([]string{s.Domain}, s.AutocertDomains...)
+
+	mgr := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(s.AutocertCacheDir),
+	}
+
+	handler := mgr.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := "https://" + r.Host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}))
+
+	return &autocertManager{
+		mgr: mgr,
+		challengeSrv: &http.Server{
+			Addr:              ":80",
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}, nil
+}
+
+// TLSConfig returns the *tls.Config ServeTLS should use to terminate
+// autocert-issued certificates.
+func (a *autocertManager) TLSConfig() *tls.Config {
+	return a.mgr.TLSConfig()
+}
+
+// serveHTTPChallengeAndRedirect serves the ACME HTTP-01 challenge on :80
+// and redirects every other request to https, as autocert requires a
+// plain HTTP listener on the standard port to complete validation. It
+// blocks until Shutdown is called.
+func (a *autocertManager) serveHTTPChallengeAndRedirect() {
+	if err := a.challengeSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("[WARN] autocert challenge server terminated, %v", err)
+	}
+}
+
+// Shutdown stops the challenge server alongside the main listener so an
+// autocert-mode restart can rebind :80 instead of leaving it held open.
+func (a *autocertManager) Shutdown(ctx context.Context) error {
+	return a.challengeSrv.Shutdown(ctx)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// defaultTokenTTL is used when Server.TokenTTL is left unset, same
+// zero-value-falls-back-to-a-default treatment as the upload limits in
+// configureUploads.
+const defaultTokenTTL = 15 * time.Minute
+
+// recipientClaims are embedded in a signed recipient link so a sender can
+// share one opaque "/r/{token}" URL instead of a raw key/pin pair. Pin
+// travels as plain text, not a hash: Messager.LoadMessage needs the raw
+// pin to verify against the stored message, and a hash can't be reversed
+// into it. The JWT's HS256 signature (Server.SigningKey) stops a holder
+// from forging or altering the claims, but not from reading them, so this
+// is only as safe as the link's transport and the recipient's inbox.
+type recipientClaims struct {
+	Key string `json:"key"`
+	Pin string `json:"pin"`
+	jwt.RegisteredClaims
+}
+
+// issueRecipientToken signs a compact JWT carrying key, pin and a fresh
+// jti, valid for Server.TokenTTL.
+func (s Server) issueRecipientToken(key, pin string) (string, error) {
+	if len(s.SigningKey) == 0 {
+		return "", errors.New("signing key not configured")
+	}
+
+	ttl := s.TokenTTL
+	if ttl == 0 {
+		ttl = defaultTokenTTL
+	}
+
+	now := time.Now()
+	claims := recipientClaims{
+		Key: key,
+		Pin: pin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.SigningKey)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign recipient token")
+	}
+	return signed, nil
+}
+
+// redeemedTokens is the process-wide jti burn list; a recipient link must
+// be redeemable exactly once, and the short TokenTTL makes an in-memory,
+// non-persistent list an acceptable trade-off across restarts.
+var redeemedTokens = newTokenLRU(10_000)
+
+// GET /r/{token}
+func (s Server) loadRecipientLinkCtrl(w http.ResponseWriter, r *http.Request) {
+	raw := chi.URLParam(r, "token")
+
+	claims := &recipientClaims{}
+	parsed, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (interface{}, error) {
+		return s.SigningKey, nil
+	})
+	if err != nil || !parsed.Valid {
+		log.Printf("[WARN] invalid recipient token, %v", err)
+		writeProblem(w, r, http.StatusBadRequest, "token_invalid", "invalid or expired link")
+		return
+	}
+
+	if redeemedTokens.seen(claims.ID, time.Until(claims.ExpiresAt.Time)) {
+		log.Printf("[WARN] replay of recipient token %s", claims.ID)
+		writeProblem(w, r, http.StatusGone, "token_replayed", "link already used")
+		return
+	}
+
+	pinAttemptsTotal.Inc()
+	msg, err := s.Messager.LoadMessage(claims.Key, claims.Pin)
+	messagesReadTotal.WithLabelValues(readResult(err)).Inc()
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "message_load_failed", err.Error())
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, JSON{"key": msg.Key, "message": string(msg.Data)}, false)
+}
+
+// tokenLRU is a tiny in-memory, TTL-bounded set used to burn a jti on its
+// first redemption attempt, before the message is loaded, so replays fail
+// closed.
+type tokenLRU struct {
+	mu     sync.Mutex
+	max    int
+	expiry map[string]time.Time
+}
+
+func newTokenLRU(max int) *tokenLRU {
+	return &tokenLRU{max: max, expiry: map[string]time.Time{}}
+}
+
+// seen records jti as redeemed and reports whether it had already been
+// seen. ttl only needs to outlive the token's own expiry, past which a
+// replay would be rejected by the token's own exp check anyway.
+func (l *tokenLRU) seen(jti string, ttl time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for k, exp := range l.expiry {
+		if now.After(exp) {
+			delete(l.expiry, k)
+		}
+	}
+
+	if _, ok := l.expiry[jti]; ok {
+		return true
+	}
+
+	if len(l.expiry) >= l.max {
+		var oldest string
+		var oldestExp time.Time
+		for k, exp := range l.expiry {
+			if oldest == "" || exp.Before(oldestExp) {
+				oldest, oldestExp = k, exp
+			}
+		}
+		delete(l.expiry, oldest)
+	}
+
+	l.expiry[jti] = now.Add(ttl)
+	return false
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenLRUSeenBurnsJTIOnce(t *testing.T) {
+	l := newTokenLRU(10)
+
+	if l.seen("jti-1", time.Minute) {
+		t.Fatal("first sighting of jti-1 should not be reported as already seen")
+	}
+	if !l.seen("jti-1", time.Minute) {
+		t.Fatal("second sighting of jti-1 should be reported as a replay")
+	}
+}
+
+func TestTokenLRUSeenExpiresEntries(t *testing.T) {
+	l := newTokenLRU(10)
+
+	l.seen("jti-1", -time.Minute) // already expired
+	if l.seen("jti-1", time.Minute) {
+		t.Fatal("expired jti-1 should be treated as unseen, not a replay")
+	}
+}
+
+func TestTokenLRUSeenEvictsOldestWhenFull(t *testing.T) {
+	l := newTokenLRU(2)
+
+	l.seen("jti-1", time.Minute)
+	time.Sleep(time.Millisecond)
+	l.seen("jti-2", time.Minute)
+	time.Sleep(time.Millisecond)
+	l.seen("jti-3", time.Minute) // evicts jti-1, the oldest
+
+	if l.seen("jti-1", time.Minute) {
+		t.Fatal("jti-1 should have been evicted and so not count as a replay")
+	}
+}
+
+func TestIssueRecipientTokenDefaultsExpiryWhenTTLUnset(t *testing.T) {
+	s := Server{SigningKey: []byte("test-signing-key")}
+
+	before := time.Now()
+	token, err := s.issueRecipientToken("msg-key", "1234")
+	if err != nil {
+		t.Fatalf("issueRecipientToken failed: %v", err)
+	}
+
+	claims := &recipientClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return s.SigningKey, nil
+	}); err != nil {
+		t.Fatalf("failed to parse issued token: %v", err)
+	}
+
+	if !claims.ExpiresAt.Time.After(before) {
+		t.Fatalf("expected ExpiresAt (%v) to be after issuance time (%v) when TokenTTL is unset", claims.ExpiresAt.Time, before)
+	}
+}
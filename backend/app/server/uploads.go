@@ -0,0 +1,244 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+const (
+	defaultUploadMaxLifetime = time.Hour
+	defaultUploadMaxSize     = 64 * 1024 * 1024
+	defaultUploadMaxSessions = 1000
+)
+
+// uploadSession tracks one in-flight resumable upload, mirroring the
+// chunked blob-writer protocol used by container registries: chunks are
+// appended in order and the whole thing is sealed once on finalize.
+type uploadSession struct {
+	id        string
+	mu        sync.Mutex
+	data      []byte
+	createdAt time.Time
+}
+
+// uploadSessions is the process-wide resumable-upload registry, evicting
+// the oldest session once UploadMaxSessions is exceeded.
+type uploadSessions struct {
+	mu           sync.Mutex
+	sessions     map[string]*uploadSession
+	order        []string
+	maxSessions  int
+	maxLifetime  time.Duration
+	maxTotalSize int64
+}
+
+var uploads = newUploadSessions(defaultUploadMaxSessions, defaultUploadMaxLifetime, defaultUploadMaxSize)
+
+func newUploadSessions(maxSessions int, maxLifetime time.Duration, maxTotalSize int64) *uploadSessions {
+	return &uploadSessions{
+		sessions:     map[string]*uploadSession{},
+		maxSessions:  maxSessions,
+		maxLifetime:  maxLifetime,
+		maxTotalSize: maxTotalSize,
+	}
+}
+
+// configureUploads applies Server's upload session limits to the shared
+// registry. Run calls this once before serving so UploadMaxLifetime,
+// UploadMaxSessions and UploadMaxSize actually take effect instead of the
+// package defaults.
+func configureUploads(s Server) {
+	maxSessions := s.UploadMaxSessions
+	if maxSessions == 0 {
+		maxSessions = defaultUploadMaxSessions
+	}
+
+	maxLifetime := s.UploadMaxLifetime
+	if maxLifetime == 0 {
+		maxLifetime = defaultUploadMaxLifetime
+	}
+
+	maxTotalSize := s.UploadMaxSize
+	if maxTotalSize == 0 {
+		maxTotalSize = defaultUploadMaxSize
+	}
+
+	uploads = newUploadSessions(maxSessions, maxLifetime, maxTotalSize)
+}
+
+func (u *uploadSessions) create() *uploadSession {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.evictExpired()
+	if len(u.order) >= u.maxSessions {
+		oldest := u.order[0]
+		u.order = u.order[1:]
+		delete(u.sessions, oldest)
+	}
+
+	id := uuid.NewString()
+	sess := &uploadSession{id: id, createdAt: time.Now()}
+	u.sessions[id] = sess
+	u.order = append(u.order, id)
+	return sess
+}
+
+func (u *uploadSessions) get(id string) (*uploadSession, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.evictExpired()
+	sess, ok := u.sessions[id]
+	return sess, ok
+}
+
+func (u *uploadSessions) remove(id string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	delete(u.sessions, id)
+	for i, oid := range u.order {
+		if oid == id {
+			u.order = append(u.order[:i], u.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (u *uploadSessions) evictExpired() {
+	if u.maxLifetime == 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-u.maxLifetime)
+	fresh := u.order[:0]
+	for _, id := range u.order {
+		if sess, ok := u.sessions[id]; ok && sess.createdAt.Before(cutoff) {
+			delete(u.sessions, id)
+			continue
+		}
+		fresh = append(fresh, id)
+	}
+	u.order = fresh
+}
+
+// count reports the number of open sessions, used to populate the
+// active-upload-sessions gauge exposed at /admin/metrics.
+func (u *uploadSessions) count() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.order)
+}
+
+// POST /api/v2/uploads
+func (s Server) createUploadCtrl(w http.ResponseWriter, r *http.Request) {
+	sess := uploads.create()
+
+	w.Header().Set("Location", "/api/v2/uploads/"+sess.id)
+	w.Header().Set("Docker-Upload-UUID", sess.id)
+	writeResponse(w, r, http.StatusAccepted, JSON{"uuid": sess.id}, false)
+}
+
+// PATCH /api/v2/uploads/{uuid}
+func (s Server) patchUploadCtrl(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "uuid")
+	sess, ok := uploads.get(id)
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, "upload_not_found", "no such upload session")
+		return
+	}
+
+	start, _, rangeErr := parseContentRange(r.Header.Get("Content-Range"))
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	offset := int64(len(sess.data))
+	if rangeErr != nil || start != offset {
+		log.Printf("[WARN] out-of-order chunk for upload %s, got %d, want %d", id, start, offset)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", offset))
+		writeProblem(w, r, http.StatusRequestedRangeNotSatisfiable, "upload_range_invalid", "chunk does not continue the session offset")
+		return
+	}
+
+	chunk, err := readAll(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "upload_read_failed", err.Error())
+		return
+	}
+
+	// the client-declared Content-Range end is untrusted - only the actual
+	// accumulated length after appending bounds the session
+	if int64(len(sess.data)+len(chunk)) > uploads.maxTotalSize {
+		writeProblem(w, r, http.StatusRequestedRangeNotSatisfiable, "upload_too_large", "upload exceeds the configured max size")
+		return
+	}
+
+	sess.data = append(sess.data, chunk...)
+
+	w.Header().Set("Range", fmt.Sprintf("0-%d", len(sess.data)-1))
+	writeResponse(w, r, http.StatusAccepted, JSON{"uuid": id, "offset": len(sess.data)}, false)
+}
+
+// PUT /api/v2/uploads/{uuid}?pin=...&exp=...
+func (s Server) finalizeUploadCtrl(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "uuid")
+	sess, ok := uploads.get(id)
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, "upload_not_found", "no such upload session")
+		return
+	}
+
+	pin := r.URL.Query().Get("pin")
+	if len(pin) != s.PinSize {
+		writeProblem(w, r, http.StatusBadRequest, "pin_size_invalid", "incorrect pin size")
+		return
+	}
+
+	expSecs, err := strconv.Atoi(r.URL.Query().Get("exp"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "exp_invalid", "exp query param must be an integer number of seconds")
+		return
+	}
+
+	sess.mu.Lock()
+	data := sess.data
+	sess.mu.Unlock()
+
+	msg, err := s.Messager.MakeMessage(time.Second*time.Duration(expSecs), string(data), pin)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "message_save_failed", err.Error())
+		return
+	}
+	messagesCreatedTotal.Inc()
+	messageSizeBytes.Observe(float64(len(data)))
+
+	uploads.remove(id)
+	writeResponse(w, r, http.StatusCreated, JSON{"key": msg.Key, "exp": msg.Exp}, false)
+}
+
+// readAll reads the full request body; each chunk is already size-limited
+// by the um.SizeLimit middleware applied to every route.
+func readAll(r *http.Request) ([]byte, error) {
+	return io.ReadAll(r.Body)
+}
+
+// parseContentRange parses a "bytes start-end/*" Content-Range header as
+// sent by resumable upload clients.
+func parseContentRange(header string) (start, end int64, err error) {
+	_, parseErr := fmt.Sscanf(header, "bytes %d-%d/*", &start, &end)
+	if parseErr != nil {
+		return 0, 0, parseErr
+	}
+	return start, end, nil
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/umputun/secrets/backend/app/store"
+	"github.com/umputun/secrets/backend/app/store/engine"
+)
+
+func init() {
+	Register("bolt", openBolt)
+}
+
+// openBolt opens the built-in BoltDB engine. The dsn's path is used as the
+// database file location, e.g. "bolt:///var/lib/secrets/secrets.db".
+func openBolt(_ context.Context, dsn string, _ map[string]interface{}) (store.Store, error) {
+	path := strings.TrimPrefix(dsn, "bolt://")
+	if path == "" {
+		return nil, errors.New("bolt: dsn missing file path")
+	}
+
+	return engine.NewBoltDB(path)
+}
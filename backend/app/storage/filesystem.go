@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/umputun/secrets/backend/app/store"
+)
+
+func init() {
+	Register("file", openFilesystem)
+}
+
+// openFilesystem opens a plain-directory store, e.g. "file:///var/lib/secrets/data".
+// Each message is written as a JSON file named after its key, making the
+// store trivial to back up or inspect without any extra tooling.
+func openFilesystem(_ context.Context, dsn string, _ map[string]interface{}) (store.Store, error) {
+	dir := strings.TrimPrefix(dsn, "file://")
+	if dir == "" {
+		return nil, errors.New("file: dsn missing directory path")
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create store dir %q", dir)
+	}
+
+	return &filesystemStore{dir: dir}, nil
+}
+
+type filesystemStore struct {
+	dir string
+}
+
+// path rejects anything that isn't a single plain path component before
+// joining it under dir, so a key like "../../etc/passwd" can't escape the
+// store directory.
+func (s *filesystemStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || key == "." || key == ".." {
+		return "", errors.Errorf("file: invalid key %q", key)
+	}
+	return filepath.Join(s.dir, key+".json"), nil
+}
+
+func (s *filesystemStore) Save(msg *store.Message) error {
+	path, err := s.path(msg.Key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal message")
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (s *filesystemStore) Load(key string) (*store.Message, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, store.ErrNoSuchKey
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read message file")
+	}
+
+	msg := &store.Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal message")
+	}
+	return msg, nil
+}
+
+func (s *filesystemStore) Remove(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *filesystemStore) Health(_ context.Context) error {
+	_, err := os.Stat(s.dir)
+	return errors.Wrap(err, "store dir not reachable")
+}
+
+// Size sums the size of every message file under dir, backing the
+// secrets_store_size_bytes gauge.
+func (s *filesystemStore) Size() (int64, error) {
+	var total int64
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read store dir")
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to stat store entry")
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
@@ -0,0 +1,27 @@
+package storage
+
+import "testing"
+
+func TestFilesystemStorePathRejectsTraversal(t *testing.T) {
+	s := &filesystemStore{dir: "/tmp/secrets-test"}
+
+	bad := []string{"", ".", "..", "../etc/passwd", "a/../../b", "/etc/passwd", "sub/key"}
+	for _, key := range bad {
+		if _, err := s.path(key); err == nil {
+			t.Errorf("path(%q) expected an error, got none", key)
+		}
+	}
+}
+
+func TestFilesystemStorePathAcceptsPlainKey(t *testing.T) {
+	s := &filesystemStore{dir: "/tmp/secrets-test"}
+
+	got, err := s.path("abc123")
+	if err != nil {
+		t.Fatalf("path(%q) unexpected error: %v", "abc123", err)
+	}
+	want := "/tmp/secrets-test/abc123.json"
+	if got != want {
+		t.Errorf("path(%q) = %q, want %q", "abc123", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/umputun/secrets/backend/app/store"
+	"github.com/umputun/secrets/backend/app/store/engine"
+)
+
+func init() {
+	Register("memory", openMemory)
+}
+
+// openMemory opens the built-in in-memory engine. opts are ignored, the
+// dsn carries no state beyond the "memory://" scheme.
+func openMemory(_ context.Context, _ string, _ map[string]interface{}) (store.Store, error) {
+	return engine.NewMemory(), nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/umputun/secrets/backend/app/store"
+)
+
+func init() {
+	Register("redis", openRedis)
+}
+
+// openRedis opens a Redis-backed store, e.g. "redis://host:6379/0?ttl=1h".
+// ttl, if set, caps how long a message may live in Redis regardless of the
+// expiration requested by the caller; it defaults to no extra cap.
+func openRedis(ctx context.Context, dsn string, opts map[string]interface{}) (store.Store, error) {
+	// ttl is this driver's own option; redis.ParseURL rejects query options
+	// it does not know, so it is dropped from the url handed to it
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse redis dsn %q", dsn)
+	}
+	q := u.Query()
+	q.Del("ttl")
+	u.RawQuery = q.Encode()
+
+	redisOpts, err := redis.ParseURL(u.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse redis dsn %q", dsn)
+	}
+
+	var ttl time.Duration
+	if v, ok := opts["ttl"].(string); ok {
+		ttl, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid ttl %q", v)
+		}
+	}
+
+	client := redis.NewClient(redisOpts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to connect to redis")
+	}
+
+	return &redisStore{client: client, ttl: ttl}, nil
+}
+
+type redisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func (s *redisStore) Save(msg *store.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal message")
+	}
+
+	ttl := s.ttl
+	if exp := time.Until(msg.Exp); ttl == 0 || exp < ttl {
+		ttl = exp
+	}
+
+	return s.client.Set(context.Background(), msg.Key, data, ttl).Err()
+}
+
+func (s *redisStore) Load(key string) (*store.Message, error) {
+	data, err := s.client.Get(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, store.ErrNoSuchKey
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load message")
+	}
+
+	msg := &store.Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal message")
+	}
+	return msg, nil
+}
+
+func (s *redisStore) Remove(key string) error {
+	return s.client.Del(context.Background(), key).Err()
+}
+
+func (s *redisStore) Health(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
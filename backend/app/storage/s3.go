@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pkg/errors"
+
+	"github.com/umputun/secrets/backend/app/store"
+)
+
+func init() {
+	Register("s3", openS3)
+}
+
+// openS3 opens an S3-compatible object-storage store, e.g.
+// "s3://my-bucket/secrets?endpoint=https://minio.local&region=us-east-1".
+// Each message is stored as a single object keyed by its message key.
+func openS3(ctx context.Context, dsn string, opts map[string]interface{}) (store.Store, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse dsn %q", dsn)
+	}
+
+	bucket, prefix := u.Host, strings.Trim(u.Path, "/")
+	if bucket == "" {
+		return nil, errors.Errorf("s3: dsn %q missing bucket", dsn)
+	}
+
+	cfgOpts := []func(*config.LoadOptions) error{}
+	if region, ok := opts["region"].(string); ok {
+		cfgOpts = append(cfgOpts, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, cfgOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load aws config")
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint, ok := opts["endpoint"].(string); ok {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+		o.UsePathStyle = true
+	})
+
+	return &s3Store{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+type s3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (s *s3Store) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+func (s *s3Store) Save(msg *store.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal message")
+	}
+
+	_, err = s.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(msg.Key)),
+		Body:   bytes.NewReader(data),
+	})
+	return errors.Wrap(err, "failed to put object")
+}
+
+func (s *s3Store) Load(key string) (*store.Message, error) {
+	out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			return nil, store.ErrNoSuchKey
+		}
+		return nil, errors.Wrap(err, "failed to get object")
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read object body")
+	}
+
+	msg := &store.Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal message")
+	}
+	return msg, nil
+}
+
+func (s *s3Store) Remove(key string) error {
+	_, err := s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	return errors.Wrap(err, "failed to delete object")
+}
+
+func (s *s3Store) Health(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	return errors.Wrap(err, "bucket not reachable")
+}
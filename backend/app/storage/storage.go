@@ -0,0 +1,174 @@
+// Package storage provides a pluggable registry of secret-store backends.
+// Drivers are registered by name and opened from a URL-style DSN, e.g.
+// "redis://host:6379/0?ttl=1h" or "bolt:///var/lib/secrets/secrets.db",
+// so Server can mix and match backends without a recompile.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/umputun/secrets/backend/app/store"
+)
+
+// Factory opens a store.Store for the given dsn and driver-specific options
+// parsed out of the dsn's query string.
+type Factory func(ctx context.Context, dsn string, opts map[string]interface{}) (store.Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Factory{}
+)
+
+// Register makes a driver available under name. It panics on a nil factory
+// or a duplicate name, mirroring database/sql's driver registration.
+func Register(name string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+func lookup(name string) (Factory, bool) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	f, ok := drivers[name]
+	return f, ok
+}
+
+// Manager owns a primary store and an optional secondary store used as a
+// mirror on writes and a failover on reads. Both are opened from driver
+// DSNs registered via Register.
+type Manager struct {
+	Primary   store.Store
+	Secondary store.Store
+}
+
+// New opens primaryDSN as the Manager's primary store, and secondaryDSN,
+// if not empty, as its secondary (mirror/failover) store.
+func New(ctx context.Context, primaryDSN, secondaryDSN string) (*Manager, error) {
+	primary, err := open(ctx, primaryDSN)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open primary store %q", primaryDSN)
+	}
+
+	m := &Manager{Primary: primary}
+
+	if secondaryDSN != "" {
+		secondary, err := open(ctx, secondaryDSN)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open secondary store %q", secondaryDSN)
+		}
+		m.Secondary = secondary
+	}
+
+	return m, nil
+}
+
+func open(ctx context.Context, dsn string) (store.Store, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse dsn %q", dsn)
+	}
+
+	factory, ok := lookup(u.Scheme)
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown driver %q", u.Scheme)
+	}
+
+	opts := map[string]interface{}{}
+	for k, v := range u.Query() {
+		if len(v) == 1 {
+			opts[k] = v[0]
+			continue
+		}
+		opts[k] = v
+	}
+
+	return factory(ctx, dsn, opts)
+}
+
+// Health checks the primary store and, if configured, the secondary. A
+// secondary failure degrades the manager but isn't fatal since reads and
+// writes can still be served from the primary alone.
+func (m *Manager) Health(ctx context.Context) error {
+	if err := healthCheck(ctx, m.Primary); err != nil {
+		return errors.Wrap(err, "primary store unhealthy")
+	}
+
+	if m.Secondary != nil {
+		if err := healthCheck(ctx, m.Secondary); err != nil {
+			log.Printf("[WARN] secondary store unhealthy, %v", err)
+		}
+	}
+
+	return nil
+}
+
+func healthCheck(ctx context.Context, s store.Store) error {
+	hc, ok := s.(interface{ Health(context.Context) error })
+	if !ok {
+		return nil // driver doesn't support health checks, assume healthy
+	}
+	return hc.Health(ctx)
+}
+
+// Save implements store.Store, writing to the primary and, if configured,
+// mirroring the same message to the secondary. A mirror failure is logged
+// but doesn't fail the call - the primary write is what the caller relies on.
+func (m *Manager) Save(msg *store.Message) error {
+	if err := m.Primary.Save(msg); err != nil {
+		return errors.Wrap(err, "primary store save failed")
+	}
+
+	if m.Secondary != nil {
+		if err := m.Secondary.Save(msg); err != nil {
+			log.Printf("[WARN] secondary store save failed, %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Load implements store.Store, reading from the primary and falling over
+// to the secondary, if configured, when the primary errors.
+func (m *Manager) Load(key string) (*store.Message, error) {
+	msg, err := m.Primary.Load(key)
+	if err == nil {
+		return msg, nil
+	}
+
+	if m.Secondary == nil {
+		return nil, err
+	}
+
+	log.Printf("[WARN] primary store load failed, failing over to secondary, %v", err)
+	return m.Secondary.Load(key)
+}
+
+// Remove implements store.Store, removing from the primary and, if
+// configured, the secondary. Only the primary's error is returned; a
+// secondary failure is logged but doesn't fail the call.
+func (m *Manager) Remove(key string) error {
+	err := m.Primary.Remove(key)
+
+	if m.Secondary != nil {
+		if secErr := m.Secondary.Remove(key); secErr != nil {
+			log.Printf("[WARN] secondary store remove failed, %v", secErr)
+		}
+	}
+
+	return errors.Wrap(err, "primary store remove failed")
+}